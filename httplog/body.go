@@ -0,0 +1,181 @@
+package httplog
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// truncatedMarker is appended to a captured body once it has been cut off at MaxBodyLog bytes.
+const truncatedMarker = "...truncated"
+
+// FnClassifyBody transforms a decoded body before it is logged, e.g. to base64-encode or placeholder
+// binary payloads so they don't blow up log size. contentType is the value of the Content-Type header,
+// if any. Defaults to DefaultClassifyBody.
+type FnClassifyBody func(body []byte, contentType string) []byte
+
+// DefaultClassifyBody replaces bodies whose content type looks binary (images, octet-stream, multipart
+// form data, audio/video) with a short "<binary N bytes>" placeholder, leaving text-like bodies (json,
+// form, plain text, xml, ...) untouched.
+func DefaultClassifyBody(body []byte, contentType string) []byte {
+	if !isBinaryContentType(contentType) {
+		return body
+	}
+
+	return []byte(fmt.Sprintf("<binary %d bytes>", len(body)))
+}
+
+// Base64ClassifyBody base64-encodes bodies whose content type looks binary, instead of placeholding
+// them. Useful when the binary payload itself needs to be inspectable in logs.
+func Base64ClassifyBody(body []byte, contentType string) []byte {
+	if !isBinaryContentType(contentType) {
+		return body
+	}
+
+	return []byte(base64.StdEncoding.EncodeToString(body))
+}
+
+func isBinaryContentType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch {
+	case strings.HasPrefix(mediaType, "image/"):
+		return true
+	case strings.HasPrefix(mediaType, "audio/"):
+		return true
+	case strings.HasPrefix(mediaType, "video/"):
+		return true
+	case strings.HasPrefix(mediaType, "multipart/form-data"):
+		return true
+	case mediaType == "application/octet-stream":
+		return true
+	default:
+		return false
+	}
+}
+
+// decodeBody transparently decodes a gzip/deflate/br encoded body. ok is false when contentEncoding
+// names a supported encoding but decoding failed - typically because MaxBodyLog truncated the captured
+// bytes mid-frame before the encoded stream was complete. Callers must not treat body as meaningful text
+// when ok is false; it is still the (undecoded) input bytes, returned so the caller can decide what to
+// do with them.
+func decodeBody(body []byte, contentEncoding string) (decoded []byte, ok bool) {
+	var r io.Reader
+
+	switch strings.ToLower(contentEncoding) {
+	case "":
+		return body, true
+	case "gzip":
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return body, false
+		}
+
+		r = gz
+	case "deflate":
+		r = flate.NewReader(bytes.NewReader(body))
+	case "br":
+		r = brotli.NewReader(bytes.NewReader(body))
+	default:
+		return body, true
+	}
+
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		return body, false
+	}
+
+	return decoded, true
+}
+
+// prepareBodyForLog decodes any content-encoding, then classifies/redacts the result, ready to be
+// attached to the log context. A body whose declared encoding couldn't be decoded (e.g. truncated
+// mid-frame) is never logged as its raw encoded bytes - those aren't meaningful text and would otherwise
+// slip past isBinaryContentType, which only looks at Content-Type - it is placeholdered instead.
+func prepareBodyForLog(body []byte, header http.Header, classify FnClassifyBody, redact FnRedactBody) []byte {
+	contentType := header.Get("Content-Type")
+
+	decoded, ok := decodeBody(body, header.Get("Content-Encoding"))
+	if !ok {
+		return []byte(fmt.Sprintf("<encoded %d bytes, failed to decode>", len(body)))
+	}
+
+	body = decoded
+
+	if classify == nil {
+		classify = DefaultClassifyBody
+	}
+
+	body = classify(body, contentType)
+
+	if redact != nil {
+		body = redact(body, contentType)
+	}
+
+	return body
+}
+
+// limitedBuffer collects up to max bytes written to it, including the eventual truncatedMarker - so
+// content itself is capped at max-len(truncatedMarker), leaving room for the marker to land inside the
+// budget instead of being sliced off by a later MaxBodyLog-bounded truncation (e.g. logger.AddBytes).
+// Once the limit is reached it stops buffering further writes (so the full response is never held in
+// memory) and appends truncatedMarker once.
+type limitedBuffer struct {
+	max       uint32
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func newLimitedBuffer(max uint32) *limitedBuffer {
+	return &limitedBuffer{max: max}
+}
+
+func (b *limitedBuffer) contentMax() int {
+	contentMax := int(b.max) - len(truncatedMarker)
+	if contentMax < 0 {
+		return 0
+	}
+
+	return contentMax
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.truncated {
+		return len(p), nil
+	}
+
+	remaining := b.contentMax() - b.buf.Len()
+	if remaining <= 0 {
+		b.truncated = true
+
+		b.buf.WriteString(truncatedMarker)
+
+		return len(p), nil
+	}
+
+	if len(p) > remaining {
+		b.buf.Write(p[:remaining])
+
+		b.truncated = true
+
+		b.buf.WriteString(truncatedMarker)
+
+		return len(p), nil
+	}
+
+	b.buf.Write(p)
+
+	return len(p), nil
+}
+
+func (b *limitedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
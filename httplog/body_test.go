@@ -0,0 +1,188 @@
+package httplog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bir/iken/httplog/logger"
+)
+
+func gzipBytes(t *testing.T, s string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(s)); err != nil {
+		t.Fatalf("gzip write: %v", err)
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestDecodeBody(t *testing.T) {
+	t.Run("no encoding passes through", func(t *testing.T) {
+		decoded, ok := decodeBody([]byte("hello"), "")
+		if !ok || string(decoded) != "hello" {
+			t.Fatalf("got (%q, %v)", decoded, ok)
+		}
+	})
+
+	t.Run("unknown encoding passes through", func(t *testing.T) {
+		decoded, ok := decodeBody([]byte("hello"), "x-custom")
+		if !ok || string(decoded) != "hello" {
+			t.Fatalf("got (%q, %v)", decoded, ok)
+		}
+	})
+
+	t.Run("valid gzip decodes", func(t *testing.T) {
+		body := gzipBytes(t, `{"hello":"world"}`)
+
+		decoded, ok := decodeBody(body, "gzip")
+		if !ok || string(decoded) != `{"hello":"world"}` {
+			t.Fatalf("got (%q, %v)", decoded, ok)
+		}
+	})
+
+	t.Run("truncated gzip fails rather than returning raw bytes", func(t *testing.T) {
+		body := gzipBytes(t, `{"hello":"a pretty long value so the frame spans several bytes"}`)
+		truncated := body[:len(body)/2]
+
+		decoded, ok := decodeBody(truncated, "gzip")
+		if ok {
+			t.Fatalf("expected ok=false for a mid-frame truncated gzip body, got %q", decoded)
+		}
+	})
+}
+
+func TestPrepareBodyForLog(t *testing.T) {
+	t.Run("decodes before classifying", func(t *testing.T) {
+		body := gzipBytes(t, `{"hello":"world"}`)
+
+		header := http.Header{}
+		header.Set("Content-Type", "application/json")
+		header.Set("Content-Encoding", "gzip")
+
+		got := prepareBodyForLog(body, header, nil, nil)
+		if string(got) != `{"hello":"world"}` {
+			t.Fatalf("got %q", got)
+		}
+	})
+
+	t.Run("failed decode is placeholdered, never logged as raw encoded bytes", func(t *testing.T) {
+		body := gzipBytes(t, `{"hello":"a pretty long value so the frame spans several bytes"}`)
+		truncated := body[:len(body)/2]
+
+		header := http.Header{}
+		header.Set("Content-Type", "application/json")
+		header.Set("Content-Encoding", "gzip")
+
+		got := prepareBodyForLog(truncated, header, nil, nil)
+		if bytes.Equal(got, truncated) {
+			t.Fatalf("placeholder must not be the raw truncated encoded bytes")
+		}
+
+		want := "<encoded " + strconv.Itoa(len(truncated)) + " bytes, failed to decode>"
+		if string(got) != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("binary content type is classified after decoding", func(t *testing.T) {
+		body := gzipBytes(t, "binary-ish-payload")
+
+		header := http.Header{}
+		header.Set("Content-Type", "application/octet-stream")
+		header.Set("Content-Encoding", "gzip")
+
+		got := prepareBodyForLog(body, header, nil, nil)
+		want := "<binary 18 bytes>"
+		if string(got) != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	})
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	t.Run("under the limit is untouched", func(t *testing.T) {
+		b := newLimitedBuffer(20)
+
+		if _, err := b.Write([]byte("hello")); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+
+		if string(b.Bytes()) != "hello" {
+			t.Fatalf("got %q", b.Bytes())
+		}
+	})
+
+	t.Run("over the limit is cut and marked, leaving room for the marker within max", func(t *testing.T) {
+		b := newLimitedBuffer(20)
+
+		if _, err := b.Write([]byte("hello world")); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+
+		want := "hello wo" + truncatedMarker
+		if string(b.Bytes()) != want {
+			t.Fatalf("got %q, want %q", b.Bytes(), want)
+		}
+
+		if len(b.Bytes()) > 20 {
+			t.Fatalf("Bytes() returned %d bytes, want <= max (20)", len(b.Bytes()))
+		}
+	})
+
+	t.Run("writes after truncation are dropped", func(t *testing.T) {
+		b := newLimitedBuffer(20)
+
+		if _, err := b.Write([]byte("hello world")); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+
+		if _, err := b.Write([]byte("more")); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+
+		want := "hello wo" + truncatedMarker
+		if string(b.Bytes()) != want {
+			t.Fatalf("got %q, want %q", b.Bytes(), want)
+		}
+	})
+}
+
+// TestLimitedBufferSurvivesDownstreamTruncation exercises limitedBuffer and logger.AddBytes together, the
+// way request.go's deferred response-logging block does: limitedBuffer caps the streamed response, then
+// logger.AddBytesToContext re-truncates at the same MaxBodyLog. Regression test for the marker being
+// silently sliced off because both truncations used to share the same boundary.
+func TestLimitedBufferSurvivesDownstreamTruncation(t *testing.T) {
+	const max = 20
+
+	b := newLimitedBuffer(max)
+
+	if _, err := b.Write([]byte("this response body is much longer than the limit")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	fl := newFakeLogger()
+
+	l := logger.AddBytes(fl, "response", b.Bytes(), max)
+	l.Log(logger.InfoLevel, "done")
+
+	body, _ := fl.state.fields["response.body"].(string)
+	if !strings.HasSuffix(body, truncatedMarker) {
+		t.Fatalf("response.body = %q, want it to end with %q", body, truncatedMarker)
+	}
+
+	if fl.state.fields["response.truncated"] != true {
+		t.Fatalf("response.truncated = %v, want true", fl.state.fields["response.truncated"])
+	}
+}
@@ -0,0 +1,37 @@
+//go:build !loghttp
+
+package httplog
+
+import "github.com/rs/zerolog"
+
+// FileSinkConfig is a no-op stub when built without the loghttp tag, so callers compile unchanged.
+type FileSinkConfig struct {
+	Path       string
+	MaxSizeMB  int
+	MaxBackups int
+	MaxAgeDays int
+	Compress   bool
+}
+
+// FileSink is a no-op stub when built without the loghttp tag.
+type FileSink struct{}
+
+// NewFileSink returns a no-op FileSink stub.
+func NewFileSink(FileSinkConfig) *FileSink {
+	return &FileSink{}
+}
+
+// Write discards all input in the stub build.
+func (s *FileSink) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// Reload is a no-op in the stub build.
+func (s *FileSink) Reload(FileSinkConfig) error {
+	return nil
+}
+
+// NewAccessLogger returns a disabled zerolog.Logger and a no-op FileSink stub.
+func NewAccessLogger(FileSinkConfig) (zerolog.Logger, *FileSink) {
+	return zerolog.Nop(), &FileSink{}
+}
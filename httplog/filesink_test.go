@@ -0,0 +1,93 @@
+//go:build loghttp
+
+package httplog
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestFileSinkWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+
+	sink := NewFileSink(FileSinkConfig{Path: path})
+
+	if _, err := sink.Write([]byte("hello\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	if string(got) != "hello\n" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestFileSinkReloadSwapsWithoutDroppingWrites(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "old.log")
+	newPath := filepath.Join(dir, "new.log")
+
+	sink := NewFileSink(FileSinkConfig{Path: oldPath})
+
+	const writesBeforeReload = 50
+	const writesAfterReload = 50
+
+	var wg sync.WaitGroup
+
+	wg.Add(writesBeforeReload)
+
+	for i := 0; i < writesBeforeReload; i++ {
+		go func() {
+			defer wg.Done()
+
+			if _, err := sink.Write([]byte("x")); err != nil {
+				t.Errorf("write before reload: %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if err := sink.Reload(FileSinkConfig{Path: newPath}); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+
+	wg.Add(writesAfterReload)
+
+	for i := 0; i < writesAfterReload; i++ {
+		go func() {
+			defer wg.Done()
+
+			if _, err := sink.Write([]byte("y")); err != nil {
+				t.Errorf("write after reload: %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	oldContent, err := os.ReadFile(oldPath)
+	if err != nil {
+		t.Fatalf("read old: %v", err)
+	}
+
+	newContent, err := os.ReadFile(newPath)
+	if err != nil {
+		t.Fatalf("read new: %v", err)
+	}
+
+	if len(oldContent) != writesBeforeReload {
+		t.Fatalf("old log has %d bytes, want %d (writes must not be dropped across Reload)", len(oldContent), writesBeforeReload)
+	}
+
+	if len(newContent) != writesAfterReload {
+		t.Fatalf("new log has %d bytes, want %d", len(newContent), writesAfterReload)
+	}
+}
@@ -0,0 +1,38 @@
+package logger
+
+import "context"
+
+// AddBytes attaches body to l under key, mirroring the field schema of logctx.AddBytes so
+// dashboards/queries built against that schema keep working regardless of which Logger backend is in
+// use:
+//
+//	<key>.size          - the full, untruncated length of body
+//	<key>.body          - body as a string, cut to max bytes
+//	<key>.truncated     - present (true) only when body was cut
+//	<key>.truncatedSize - present only when body was cut, set to max
+func AddBytes(l Logger, key string, body []byte, max uint32) Logger {
+	size := len(body)
+
+	fields := map[string]any{
+		key + ".size": size,
+	}
+
+	if uint32(size) > max {
+		fields[key+".body"] = string(body[:max])
+		fields[key+".truncated"] = true
+		fields[key+".truncatedSize"] = max
+	} else {
+		fields[key+".body"] = string(body)
+	}
+
+	return l.WithFields(fields)
+}
+
+// AddBytesToContext is the context-bound equivalent of AddBytes, for use once the handler has already
+// returned and fields can no longer be threaded through as a return value. Mirrors
+// logctx.AddBytesToContext.
+func AddBytesToContext(ctx context.Context, key string, body []byte, max uint32) {
+	UpdateContext(ctx, func(l Logger) Logger {
+		return AddBytes(l, key, body, max)
+	})
+}
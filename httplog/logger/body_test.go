@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestAddBytes(t *testing.T) {
+	t.Run("body under the limit", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		l := NewSlog(slog.New(slog.NewJSONHandler(&buf, nil)))
+		l = AddBytes(l, "http.request", []byte("hello"), 10)
+		l.Log(InfoLevel, "done")
+
+		got := decodeLogLine(t, buf.Bytes())
+
+		if got["http.request.size"] != float64(5) {
+			t.Fatalf("size = %v, want 5", got["http.request.size"])
+		}
+
+		if got["http.request.body"] != "hello" {
+			t.Fatalf("body = %v, want %q", got["http.request.body"], "hello")
+		}
+
+		if _, present := got["http.request.truncated"]; present {
+			t.Fatalf("truncated should not be present for an untruncated body, got %v", got["http.request.truncated"])
+		}
+
+		if _, present := got["http.request.truncatedSize"]; present {
+			t.Fatalf("truncatedSize should not be present for an untruncated body, got %v", got["http.request.truncatedSize"])
+		}
+	})
+
+	t.Run("body over the limit", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		l := NewSlog(slog.New(slog.NewJSONHandler(&buf, nil)))
+		l = AddBytes(l, "http.response", []byte("hello world"), 5)
+		l.Log(InfoLevel, "done")
+
+		got := decodeLogLine(t, buf.Bytes())
+
+		if got["http.response.size"] != float64(11) {
+			t.Fatalf("size = %v, want 11", got["http.response.size"])
+		}
+
+		if got["http.response.body"] != "hello" {
+			t.Fatalf("body = %v, want %q", got["http.response.body"], "hello")
+		}
+
+		if got["http.response.truncated"] != true {
+			t.Fatalf("truncated = %v, want true", got["http.response.truncated"])
+		}
+
+		if got["http.response.truncatedSize"] != float64(5) {
+			t.Fatalf("truncatedSize = %v, want 5", got["http.response.truncatedSize"])
+		}
+	})
+}
+
+func decodeLogLine(t *testing.T, line []byte) map[string]any {
+	t.Helper()
+
+	var got map[string]any
+	if err := json.Unmarshal(line, &got); err != nil {
+		t.Fatalf("unmarshal log line: %v (line: %s)", err, line)
+	}
+
+	return got
+}
@@ -0,0 +1,51 @@
+// Package logger abstracts the structured logging backend used by httplog.RequestLogger, so the
+// middleware isn't hard-wired to zerolog. Two implementations are provided: NewZerolog (the default,
+// backed by github.com/rs/zerolog) and NewSlog (backed by log/slog, Go 1.21+). Both write the same
+// Datadog-convention attribute names, so downstream handlers can read request-scoped fields from
+// FromContext without caring which backend is in use.
+package logger
+
+import "context"
+
+// Level mirrors the handful of severities httplog needs, independent of the underlying logging library.
+type Level int
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+// Logger is the structured-logging contract httplog.RequestLogger is written against.
+type Logger interface {
+	// WithFields attaches the given key/value pairs, to be included on every subsequent Log call.
+	WithFields(fields map[string]any) Logger
+	// Log emits a single event at level with msg, including any fields accumulated via WithFields.
+	Log(level Level, msg string)
+}
+
+type ctxKey struct{}
+
+// FromContext returns the Logger attached to ctx via NewContext. If none was attached, it falls back to
+// wrapping the ambient zerolog.Logger (see NewZerolog), which preserves the zero-config behaviour
+// RequestLogger has always had.
+func FromContext(ctx context.Context) Logger {
+	if l, ok := ctx.Value(ctxKey{}).(Logger); ok {
+		return l
+	}
+
+	return NewZerolog(ctx)
+}
+
+// NewContext returns a copy of ctx with l attached, retrievable via FromContext.
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// UpdateContext mutates the Logger attached to ctx via fn, mirroring zerolog.Logger.UpdateContext.
+// Both built-in backends hold their state behind a pointer, so the mutation is visible to anyone else
+// holding the same ctx without needing to re-attach it.
+func UpdateContext(ctx context.Context, fn func(Logger) Logger) {
+	fn(FromContext(ctx))
+}
@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// slogLogger adapts a *slog.Logger to the Logger interface. The logger is held behind an atomic.Pointer
+// so that WithFields composes fields in place, the same way zerologLogger composes through zerolog's own
+// context mutation.
+type slogLogger struct {
+	logger *atomic.Pointer[slog.Logger]
+}
+
+// NewSlog wraps base as a Logger. Attach it to a request context with NewContext to have RequestLogger
+// use it instead of the default zerolog backend.
+func NewSlog(base *slog.Logger) Logger {
+	p := &atomic.Pointer[slog.Logger]{}
+	p.Store(base)
+
+	return slogLogger{logger: p}
+}
+
+func (l slogLogger) WithFields(fields map[string]any) Logger {
+	args := make([]any, 0, len(fields)*2) //nolint:gomnd
+	for k, v := range fields {
+		args = append(args, k, v)
+	}
+
+	l.logger.Store(l.logger.Load().With(args...))
+
+	return l
+}
+
+func (l slogLogger) Log(level Level, msg string) {
+	l.logger.Load().Log(context.Background(), toSlogLevel(level), msg)
+}
+
+func toSlogLevel(l Level) slog.Level {
+	switch l {
+	case ErrorLevel:
+		return slog.LevelError
+	case WarnLevel:
+		return slog.LevelWarn
+	case DebugLevel:
+		return slog.LevelDebug
+	default:
+		return slog.LevelInfo
+	}
+}
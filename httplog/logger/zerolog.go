@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// zerologLogger adapts the *zerolog.Logger bound to ctx (via zerolog.Ctx) to the Logger interface.
+// Field updates go through zerolog's own UpdateContext, so `zerolog.Ctx(ctx)` keeps working for callers
+// that still reach for zerolog directly.
+type zerologLogger struct {
+	ctx context.Context
+}
+
+// NewZerolog wraps the zerolog.Logger already bound to ctx (e.g. via zerolog.Logger.WithContext, or the
+// zerolog/hlog request middleware) as a Logger.
+func NewZerolog(ctx context.Context) Logger {
+	return zerologLogger{ctx: ctx}
+}
+
+func (l zerologLogger) WithFields(fields map[string]any) Logger {
+	zerolog.Ctx(l.ctx).UpdateContext(func(c zerolog.Context) zerolog.Context {
+		return c.Fields(fields)
+	})
+
+	return l
+}
+
+func (l zerologLogger) Log(level Level, msg string) {
+	zerolog.Ctx(l.ctx).WithLevel(toZerologLevel(level)).Ctx(l.ctx).Msg(msg)
+}
+
+func toZerologLevel(l Level) zerolog.Level {
+	switch l {
+	case ErrorLevel:
+		return zerolog.ErrorLevel
+	case WarnLevel:
+		return zerolog.WarnLevel
+	case DebugLevel:
+		return zerolog.DebugLevel
+	default:
+		return zerolog.InfoLevel
+	}
+}
@@ -0,0 +1,93 @@
+package httplog
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces the value of any header or query parameter that isn't allowlisted.
+const Redacted = "REDACTED"
+
+// DefaultAllowedHeaders mirrors the Azure SDK's default request-logging header allowlist.
+var DefaultAllowedHeaders = []string{
+	"accept",
+	"cache-control",
+	"connection",
+	"content-length",
+	"content-type",
+	"date",
+	"etag",
+	"expires",
+	"if-match",
+	"if-modified-since",
+	"if-none-match",
+	"if-unmodified-since",
+	"last-modified",
+	"pragma",
+	"request-id",
+	"retry-after",
+	"server",
+	"traceparent",
+	"transfer-encoding",
+	"user-agent",
+	"x-request-id",
+}
+
+// FnRedactBody masks sensitive fields (e.g. password, token) in a request/response body before it is
+// logged. contentType is the value of the Content-Type header, if any.
+type FnRedactBody func(body []byte, contentType string) []byte
+
+func newStringSet(values []string) map[string]struct{} {
+	m := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		m[strings.ToLower(v)] = struct{}{}
+	}
+
+	return m
+}
+
+// redactHeaders returns a copy of h with any header not present in allowed, or present in redacted,
+// replaced with Redacted. Non-allowlisted headers are kept present (so operators can see they were
+// sent) rather than dropped.
+func redactHeaders(h http.Header, allowed, redacted map[string]struct{}) http.Header {
+	out := make(http.Header, len(h))
+
+	for k, v := range h {
+		lower := strings.ToLower(k)
+
+		_, isRedacted := redacted[lower]
+		_, isAllowed := allowed[lower]
+
+		if isRedacted || !isAllowed {
+			out[k] = []string{Redacted}
+			continue
+		}
+
+		out[k] = v
+	}
+
+	return out
+}
+
+// redactQuery returns a copy of q with any parameter not present in allowed, or present in redacted,
+// replaced with Redacted.
+func redactQuery(q url.Values, allowed, redacted map[string]struct{}) url.Values {
+	out := make(url.Values, len(q))
+
+	for k, v := range q {
+		lower := strings.ToLower(k)
+
+		_, isRedacted := redacted[lower]
+		_, isAllowed := allowed[lower]
+
+		if isRedacted || !isAllowed {
+			out[k] = []string{Redacted}
+			continue
+		}
+
+		out[k] = v
+	}
+
+	return out
+}
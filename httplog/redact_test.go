@@ -0,0 +1,78 @@
+package httplog
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestRedactHeaders(t *testing.T) {
+	allowed := newStringSet([]string{"Content-Type", "X-Request-Id"})
+	redacted := newStringSet([]string{"x-request-id"})
+
+	h := http.Header{
+		"Content-Type":  {"application/json"},
+		"X-Request-Id":  {"abc-123"},
+		"Authorization": {"Bearer secret"},
+	}
+
+	got := redactHeaders(h, allowed, redacted)
+
+	if got.Get("Content-Type") != "application/json" {
+		t.Fatalf("Content-Type = %q, want passthrough", got.Get("Content-Type"))
+	}
+
+	if got.Get("X-Request-Id") != Redacted {
+		t.Fatalf("X-Request-Id = %q, want %q (explicitly redacted even though allowlisted)", got.Get("X-Request-Id"), Redacted)
+	}
+
+	if got.Get("Authorization") != Redacted {
+		t.Fatalf("Authorization = %q, want %q (not allowlisted)", got.Get("Authorization"), Redacted)
+	}
+
+	if _, present := got["Authorization"]; !present {
+		t.Fatalf("non-allowlisted header should be kept present (as Redacted), not dropped")
+	}
+}
+
+func TestRedactHeadersCaseInsensitive(t *testing.T) {
+	allowed := newStringSet([]string{"Content-Type"})
+
+	h := http.Header{"content-type": {"text/plain"}}
+
+	got := redactHeaders(h, allowed, map[string]struct{}{})
+	if v := got["content-type"]; len(v) != 1 || v[0] != "text/plain" {
+		t.Fatalf("allowlist match should be case-insensitive, got %q", v)
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+	allowed := newStringSet([]string{"page"})
+	redacted := newStringSet([]string{"page"})
+
+	q := url.Values{
+		"page":  {"2"},
+		"token": {"secret"},
+	}
+
+	got := redactQuery(q, allowed, redacted)
+
+	if got.Get("page") != Redacted {
+		t.Fatalf("page = %q, want %q (explicitly redacted even though allowlisted)", got.Get("page"), Redacted)
+	}
+
+	if got.Get("token") != Redacted {
+		t.Fatalf("token = %q, want %q (not allowlisted)", got.Get("token"), Redacted)
+	}
+}
+
+func TestRedactQueryAllowedPassesThrough(t *testing.T) {
+	allowed := newStringSet([]string{"Page"})
+
+	q := url.Values{"page": {"2"}}
+
+	got := redactQuery(q, allowed, map[string]struct{}{})
+	if got.Get("page") != "2" {
+		t.Fatalf("allowlist match should be case-insensitive, got %q", got.Get("page"))
+	}
+}
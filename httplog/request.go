@@ -2,11 +2,14 @@ package httplog
 
 import (
 	"bytes"
+	"fmt"
+	"math/rand"
+	"net"
 	"net/http"
+	"runtime"
 	"time"
 
-	"github.com/rs/zerolog"
-
+	"github.com/bir/iken/httplog/logger"
 	"github.com/bir/iken/httputil"
 	"github.com/bir/iken/logctx"
 )
@@ -23,10 +26,16 @@ const (
 	Request             = "request"
 	RequestID           = "http.request_id"
 	RequestHeaders      = "request.headers"
+	RequestQueryString  = "http.url_details.querystring"
 	RequestError        = "request.body_error"
 	Response            = "response"
 	TraceID             = "trace_id"
+	SpanID              = "span_id"
 	UserID              = "usr.id"
+	NetworkClientIP     = "network.client.ip"
+	ErrorKind           = "error.kind"
+	ErrorMessage        = "error.message"
+	ErrorStack          = "error.stack"
 )
 
 // MaxBodyLog controls the maximum request/response body that can be logged.  Anything greater will be truncated.
@@ -38,16 +47,16 @@ var now = time.Now
 // stackSkip defines the lines to skip in the stack logger - this is determined by the structure of this code.
 const stackSkip = 3
 
-type FnToLogLevel func(r *http.Request, status int) zerolog.Level
+type FnToLogLevel func(r *http.Request, status int) logger.Level
 
-func StatusToLogLevel(_ *http.Request, status int) zerolog.Level {
+func StatusToLogLevel(_ *http.Request, status int) logger.Level {
 	switch {
 	case status >= http.StatusInternalServerError:
-		return zerolog.ErrorLevel
+		return logger.ErrorLevel
 	case status >= http.StatusBadRequest:
-		return zerolog.WarnLevel
+		return logger.WarnLevel
 	default:
-		return zerolog.InfoLevel
+		return logger.InfoLevel
 	}
 }
 
@@ -56,30 +65,166 @@ func StatusToLogLevel(_ *http.Request, status int) zerolog.Level {
 // logRequestBody will log the body of the request, default is false.
 // logResponseBody will log the body of the response, default is false.  This should be disabled for large or streaming
 // results.
-type FnShouldLog func(r *http.Request) (logRequest, logRequestBody, logResponseBody bool, toLogLevel FnToLogLevel)
+// logRequestStart will emit a "request.started" log line (method, path, remote_ip, request_id, headers)
+// before the handler runs, default is false.  Useful to see in-flight requests when a handler hangs or
+// the process crashes mid-request.
+type FnShouldLog func(r *http.Request) (logRequest, logRequestBody, logResponseBody, logRequestStart bool, toLogLevel FnToLogLevel)
+
+func LogRequestBody(_ *http.Request) (bool, bool, bool, bool, FnToLogLevel) {
+	return true, true, false, false, StatusToLogLevel
+}
 
-func LogRequestBody(_ *http.Request) (bool, bool, bool, FnToLogLevel) {
-	return true, true, false, StatusToLogLevel
+func LogAll(_ *http.Request) (bool, bool, bool, bool, FnToLogLevel) {
+	return true, true, true, true, StatusToLogLevel
 }
 
-func LogAll(_ *http.Request) (bool, bool, bool, FnToLogLevel) {
-	return true, true, true, StatusToLogLevel
+// FnSkip short-circuits RequestLogger entirely for matched requests - no wrapped writer is allocated and
+// the logger context is never touched. This is stronger than FnShouldLog returning logRequest=false,
+// which still pays both of those costs; use it for high-volume endpoints like /healthz or /metrics.
+type FnSkip func(r *http.Request) bool
+
+// FnSample returns the probability (0-1) that a request should be logged. It is only consulted once the
+// response status is known, and is ignored whenever the computed log level is at least Warn, or the
+// request's trace was marked sampled by FnExtractTrace - errors and sampled traces are always logged
+// regardless of sampling.
+type FnSample func(r *http.Request) float64
+
+// options holds the optional behaviour that can be layered onto RequestLogger via Option funcs.
+type options struct {
+	extractTrace        FnExtractTrace
+	allowedHeaders      map[string]struct{}
+	redactedHeaders     map[string]struct{}
+	allowedQueryParams  map[string]struct{}
+	redactedQueryParams map[string]struct{}
+	redactBody          FnRedactBody
+	classifyBody        FnClassifyBody
+	skip                FnSkip
+	sample              FnSample
+}
+
+// Option configures optional RequestLogger behaviour.
+type Option func(*options)
+
+// WithTraceExtractor overrides how the trace-id/span-id pair is pulled off (or generated for) the
+// incoming request, e.g. to plug in an OpenTelemetry propagator. Defaults to DefaultExtractTrace.
+func WithTraceExtractor(fn FnExtractTrace) Option {
+	return func(o *options) {
+		o.extractTrace = fn
+	}
+}
+
+// WithAllowedHeaders replaces the default header allowlist (DefaultAllowedHeaders). Headers not on the
+// list are logged as Redacted rather than dropped.
+func WithAllowedHeaders(headers ...string) Option {
+	return func(o *options) {
+		o.allowedHeaders = newStringSet(headers)
+	}
+}
+
+// WithRedactedHeaders forces the given headers to be logged as Redacted even if they are allowlisted.
+func WithRedactedHeaders(headers ...string) Option {
+	return func(o *options) {
+		o.redactedHeaders = newStringSet(headers)
+	}
+}
+
+// WithAllowedQueryParams sets the query parameter allowlist. Params not on the list are logged as
+// Redacted rather than dropped. Defaults to allowing none.
+func WithAllowedQueryParams(params ...string) Option {
+	return func(o *options) {
+		o.allowedQueryParams = newStringSet(params)
+	}
+}
+
+// WithRedactedQueryParams forces the given query parameters to be logged as Redacted even if they are
+// allowlisted.
+func WithRedactedQueryParams(params ...string) Option {
+	return func(o *options) {
+		o.redactedQueryParams = newStringSet(params)
+	}
+}
+
+// WithBodyRedactor installs a hook invoked on request/response bodies before they are logged, so fields
+// such as password/token can be masked.
+func WithBodyRedactor(fn FnRedactBody) Option {
+	return func(o *options) {
+		o.redactBody = fn
+	}
+}
+
+// WithBodyClassifier overrides how binary bodies are represented in logs (placeholder vs base64).
+// Defaults to DefaultClassifyBody.
+func WithBodyClassifier(fn FnClassifyBody) Option {
+	return func(o *options) {
+		o.classifyBody = fn
+	}
+}
+
+// WithSkipper installs a FnSkip predicate that disables RequestLogger entirely for matched requests.
+func WithSkipper(fn FnSkip) Option {
+	return func(o *options) {
+		o.skip = fn
+	}
+}
+
+// WithSampler installs a FnSample hook to probabilistically drop non-error completion log lines for
+// high-volume 2xx/3xx endpoints.
+func WithSampler(fn FnSample) Option {
+	return func(o *options) {
+		o.sample = fn
+	}
 }
 
 // RequestLogger logs optional data, as specified by the shouldLog func.
-// NOTE: The zerolog context logger MUST be initialized prior to this handler invocation.   This is generally done by
-// using the recover logger, or by using the zerolog/hlog.NewHandler directly.
-func RequestLogger(shouldLog FnShouldLog) func(http.Handler) http.Handler { //nolint: funlen
+// NOTE: The httplog/logger context logger MUST be initialized prior to this handler invocation. This is
+// generally done by using the recover logger, or by using the zerolog/hlog.NewHandler directly; by
+// default the context falls back to wrapping the ambient zerolog.Logger (see logger.NewZerolog), so
+// existing zerolog-based setups keep working unchanged. To use the log/slog backend instead, attach one
+// with r = r.WithContext(logger.NewContext(r.Context(), logger.NewSlog(slogger))) upstream of this
+// middleware.
+func RequestLogger(shouldLog FnShouldLog, opts ...Option) func(http.Handler) http.Handler {
+	return newRequestLogger(shouldLog, false, opts...)
+}
+
+// RequestLoggerWithRecover behaves like RequestLogger, but additionally recovers panics raised by next.
+// A recovered panic is logged with the same attribute schema as a normal completion log, plus
+// ErrorKind/ErrorMessage/ErrorStack, a 500 is written via the wrapped writer, and the usual completion
+// log still fires afterward - unlike plain RequestLogger, whose deferred completion log runs while the
+// panic unwinds (with whatever status had been written by then) and which lets the panic propagate.
+func RequestLoggerWithRecover(shouldLog FnShouldLog, opts ...Option) func(http.Handler) http.Handler {
+	return newRequestLogger(shouldLog, true, opts...)
+}
+
+func newRequestLogger(shouldLog FnShouldLog, withRecover bool, opts ...Option) func(http.Handler) http.Handler { //nolint: funlen
+	o := options{
+		extractTrace:        DefaultExtractTrace,
+		allowedHeaders:      newStringSet(DefaultAllowedHeaders),
+		redactedHeaders:     map[string]struct{}{},
+		allowedQueryParams:  map[string]struct{}{},
+		redactedQueryParams: map[string]struct{}{},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if o.skip != nil && o.skip(r) {
+				if next != nil {
+					next.ServeHTTP(w, r)
+				}
+
+				return
+			}
+
 			start := now()
 
-			var logRequest, logRequestBody, logResponse bool
+			var logRequest, logRequestBody, logResponse, logRequestStart bool
 			logRequest = true
 			toLogLevel := StatusToLogLevel
 
 			if shouldLog != nil {
-				logRequest, logRequestBody, logResponse, toLogLevel = shouldLog(r)
+				logRequest, logRequestBody, logResponse, logRequestStart, toLogLevel = shouldLog(r)
 			}
 
 			requestID := r.Header.Get(httputil.RequestIDHeader)
@@ -94,59 +239,139 @@ func RequestLogger(shouldLog FnShouldLog) func(http.Handler) http.Handler { //no
 				return
 			}
 
-			var responseBuffer *bytes.Buffer
+			traceID, spanID, sampled := o.extractTrace(r)
+			w.Header().Set(TraceParentHeader, formatTraceParent(traceID, spanID, sampled))
+
+			var responseBuffer *limitedBuffer
 
 			wrappedWriter := httputil.WrapWriter(w)
 
 			if logResponse {
-				responseBuffer = bytes.NewBuffer(nil)
+				responseBuffer = newLimitedBuffer(MaxBodyLog)
 				wrappedWriter.Tee(responseBuffer)
 			}
 
-			zerolog.Ctx(r.Context()).UpdateContext(func(logContext zerolog.Context) zerolog.Context {
+			logger.UpdateContext(r.Context(), func(l logger.Logger) logger.Logger {
 				if logRequestBody {
-					logContext = logBody(logContext, r)
+					l = logRequestBodyFields(l, r, o.classifyBody, o.redactBody)
+				}
+
+				fields := map[string]any{
+					TraceID:            traceID,
+					SpanID:             spanID,
+					HTTPMethod:         r.Method,
+					HTTPURLDetailsPath: r.URL.Path,
+					RequestHeaders:     redactHeaders(r.Header, o.allowedHeaders, o.redactedHeaders),
 				}
 
 				if requestID != "" {
-					logContext = logContext.Str(RequestID, requestID)
+					fields[RequestID] = requestID
+				}
+
+				if r.URL.RawQuery != "" {
+					fields[RequestQueryString] = redactQuery(r.URL.Query(), o.allowedQueryParams, o.redactedQueryParams)
 				}
 
-				return logContext.
-					Str(HTTPMethod, r.Method).
-					Str(HTTPURLDetailsPath, r.URL.Path).
-					Interface(RequestHeaders, httputil.DumpHeader(r))
+				return l.WithFields(fields)
 			})
 
-			if next != nil {
-				next.ServeHTTP(wrappedWriter, r)
+			if logRequestStart {
+				logger.FromContext(r.Context()).WithFields(map[string]any{
+					NetworkClientIP: remoteIP(r),
+				}).Log(logger.InfoLevel, "request.started")
 			}
 
-			status := wrappedWriter.Status()
+			defer func() {
+				status := wrappedWriter.Status()
 
-			if logResponse {
-				logctx.AddBytesToContext(r.Context(), Response, responseBuffer.Bytes(), MaxBodyLog)
+				if logResponse {
+					respBody := prepareBodyForLog(responseBuffer.Bytes(), wrappedWriter.Header(), o.classifyBody, o.redactBody)
+					logger.AddBytesToContext(r.Context(), Response, respBody, MaxBodyLog)
+				}
+
+				level := toLogLevel(r, status)
+				if sampled && level < logger.InfoLevel {
+					level = logger.InfoLevel
+				}
+
+				if o.sample != nil && !sampled && level < logger.WarnLevel && rand.Float64() >= o.sample(r) {
+					return
+				}
+
+				logger.FromContext(r.Context()).WithFields(map[string]any{
+					HTTPStatusCode:      status,
+					NetworkBytesWritten: wrappedWriter.BytesWritten(),
+					Duration:            now().Sub(start),
+				}).Log(level, fmt.Sprintf("%d %s %s", status, r.Method, r.URL))
+			}()
+
+			if withRecover {
+				defer recoverPanic(r, wrappedWriter)
 			}
 
-			zerolog.Ctx(r.Context()).WithLevel(toLogLevel(r, status)).
-				Ctx(r.Context()).
-				Int(HTTPStatusCode, status).
-				Int(NetworkBytesWritten, wrappedWriter.BytesWritten()).
-				Dur(Duration, now().Sub(start)).Msgf("%d %s %s", status, r.Method, r.URL)
+			if next != nil {
+				next.ServeHTTP(wrappedWriter, r)
+			}
 		})
 	}
 }
 
-func logBody(l zerolog.Context, r *http.Request) zerolog.Context {
-	body, err := httputil.DumpBody(r)
+// remoteIP strips the port from r.RemoteAddr, falling back to the raw value if it isn't a host:port pair.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
-		l = l.Str(RequestError, err.Error())
-	} else {
-		size := len(body)
-		l = l.Int(NetworkBytesRead, size)
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// recoverPanic recovers a panic from next, logs it with a stack trace, and writes a 500 via w. Intended
+// to be deferred ahead of (i.e. registered after) the completion-log defer in RequestLoggerWithRecover,
+// so the completion log still reflects the 500 once this has run.
+func recoverPanic(r *http.Request, w http.ResponseWriter) {
+	rec := recover()
+	if rec == nil {
+		return
+	}
+
+	stack := make([]byte, 64*1024) //nolint:gomnd
+	stack = stack[:runtime.Stack(stack, false)]
+	stack = skipStackLines(stack, stackSkip)
+
+	logger.FromContext(r.Context()).WithFields(map[string]any{
+		ErrorKind:    "panic",
+		ErrorMessage: fmt.Sprintf("%v", rec),
+		ErrorStack:   string(stack),
+	}).Log(logger.ErrorLevel, "panic recovered")
+
+	w.WriteHeader(http.StatusInternalServerError)
+}
+
+// skipStackLines drops the first n lines of a runtime.Stack dump; with stackSkip that is the goroutine
+// header and the recoverPanic frame, which are uninteresting noise ahead of the actual panic site.
+func skipStackLines(stack []byte, n int) []byte {
+	for i := 0; i < n; i++ {
+		idx := bytes.IndexByte(stack, '\n')
+		if idx < 0 {
+			return stack
+		}
+
+		stack = stack[idx+1:]
+	}
+
+	return stack
+}
 
-		l = logctx.AddBytes(l, Request, body, MaxBodyLog)
+func logRequestBodyFields(l logger.Logger, r *http.Request, classifyBody FnClassifyBody, redactBody FnRedactBody) logger.Logger {
+	body, err := httputil.DumpBody(r)
+	if err != nil {
+		return l.WithFields(map[string]any{RequestError: err.Error()})
 	}
 
-	return l
+	l = l.WithFields(map[string]any{NetworkBytesRead: len(body)})
+
+	body = prepareBodyForLog(body, r.Header, classifyBody, redactBody)
+
+	return logger.AddBytes(l, Request, body, MaxBodyLog)
 }
@@ -0,0 +1,235 @@
+package httplog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bir/iken/httplog/logger"
+)
+
+// fakeLogRecord captures a single Log call made against a fakeLogger.
+type fakeLogRecord struct {
+	level  logger.Level
+	msg    string
+	fields map[string]any
+}
+
+// fakeLoggerState is shared (via pointer) across every value returned by WithFields, mirroring the way
+// zerologLogger/slogLogger compose fields in place rather than through an immutable copy.
+type fakeLoggerState struct {
+	fields  map[string]any
+	records []fakeLogRecord
+}
+
+type fakeLogger struct {
+	state *fakeLoggerState
+}
+
+func newFakeLogger() *fakeLogger {
+	return &fakeLogger{state: &fakeLoggerState{fields: map[string]any{}}}
+}
+
+func (l *fakeLogger) WithFields(fields map[string]any) logger.Logger {
+	for k, v := range fields {
+		l.state.fields[k] = v
+	}
+
+	return l
+}
+
+func (l *fakeLogger) Log(level logger.Level, msg string) {
+	snapshot := make(map[string]any, len(l.state.fields))
+	for k, v := range l.state.fields {
+		snapshot[k] = v
+	}
+
+	l.state.records = append(l.state.records, fakeLogRecord{level: level, msg: msg, fields: snapshot})
+}
+
+func TestRequestLoggerWithRecover(t *testing.T) {
+	fl := newFakeLogger()
+
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	mw := RequestLoggerWithRecover(LogRequestBody)(panicking)
+
+	r := httptest.NewRequest(http.MethodGet, "/widgets", nil)
+	r = r.WithContext(logger.NewContext(r.Context(), fl))
+	w := httptest.NewRecorder()
+
+	mw.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+
+	if len(fl.state.records) != 2 {
+		t.Fatalf("got %d log records, want 2 (panic + completion): %+v", len(fl.state.records), fl.state.records)
+	}
+
+	panicRecord := fl.state.records[0]
+	if panicRecord.msg != "panic recovered" || panicRecord.level != logger.ErrorLevel {
+		t.Fatalf("unexpected panic record: %+v", panicRecord)
+	}
+
+	if panicRecord.fields[ErrorKind] != "panic" || panicRecord.fields[ErrorMessage] != "boom" {
+		t.Fatalf("unexpected panic fields: %+v", panicRecord.fields)
+	}
+
+	if _, ok := panicRecord.fields[ErrorStack].(string); !ok {
+		t.Fatalf("expected %s to be a string, got %+v", ErrorStack, panicRecord.fields[ErrorStack])
+	}
+
+	completionRecord := fl.state.records[1]
+	if completionRecord.fields[HTTPStatusCode] != http.StatusInternalServerError {
+		t.Fatalf("completion log status = %v, want %d", completionRecord.fields[HTTPStatusCode], http.StatusInternalServerError)
+	}
+}
+
+func TestRequestLoggerWithRecoverNoPanic(t *testing.T) {
+	fl := newFakeLogger()
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mw := RequestLoggerWithRecover(LogRequestBody)(ok)
+
+	r := httptest.NewRequest(http.MethodGet, "/widgets", nil)
+	r = r.WithContext(logger.NewContext(r.Context(), fl))
+	w := httptest.NewRecorder()
+
+	mw.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	if len(fl.state.records) != 1 {
+		t.Fatalf("got %d log records, want 1 (completion only): %+v", len(fl.state.records), fl.state.records)
+	}
+
+	if fl.state.records[0].fields[HTTPStatusCode] != http.StatusOK {
+		t.Fatalf("completion log status = %v, want %d", fl.state.records[0].fields[HTTPStatusCode], http.StatusOK)
+	}
+}
+
+func TestRequestLoggerStartEvent(t *testing.T) {
+	fl := newFakeLogger()
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mw := RequestLogger(LogAll)(ok)
+
+	r := httptest.NewRequest(http.MethodGet, "/widgets", nil)
+	r = r.WithContext(logger.NewContext(r.Context(), fl))
+	w := httptest.NewRecorder()
+
+	mw.ServeHTTP(w, r)
+
+	if len(fl.state.records) != 2 {
+		t.Fatalf("got %d log records, want 2 (request.started + completion): %+v", len(fl.state.records), fl.state.records)
+	}
+
+	if fl.state.records[0].msg != "request.started" {
+		t.Fatalf("first record msg = %q, want %q", fl.state.records[0].msg, "request.started")
+	}
+}
+
+func TestWithSkipperShortCircuits(t *testing.T) {
+	fl := newFakeLogger()
+
+	handlerCalled := false
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerCalled = true
+		w.WriteHeader(http.StatusOK)
+	})
+
+	shouldLog := func(r *http.Request) (bool, bool, bool, bool, FnToLogLevel) {
+		t.Fatal("shouldLog must not be consulted once FnSkip matches")
+		return false, false, false, false, nil
+	}
+
+	mw := RequestLogger(shouldLog, WithSkipper(func(r *http.Request) bool { return r.URL.Path == "/healthz" }))(ok)
+
+	r := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	r = r.WithContext(logger.NewContext(r.Context(), fl))
+	w := httptest.NewRecorder()
+
+	mw.ServeHTTP(w, r)
+
+	if !handlerCalled {
+		t.Fatalf("expected the wrapped handler to still run for a skipped request")
+	}
+
+	if len(fl.state.records) != 0 {
+		t.Fatalf("got %d log records, want 0 for a skipped request: %+v", len(fl.state.records), fl.state.records)
+	}
+}
+
+func TestWithSamplerDropsLowSeverityUnsampledTraffic(t *testing.T) {
+	fl := newFakeLogger()
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mw := RequestLogger(LogRequestBody, WithSampler(func(r *http.Request) float64 { return 0 }))(ok)
+
+	r := httptest.NewRequest(http.MethodGet, "/widgets", nil)
+	r = r.WithContext(logger.NewContext(r.Context(), fl))
+	w := httptest.NewRecorder()
+
+	mw.ServeHTTP(w, r)
+
+	if len(fl.state.records) != 0 {
+		t.Fatalf("got %d log records, want 0 (sampler should have dropped the completion log): %+v", len(fl.state.records), fl.state.records)
+	}
+}
+
+func TestWithSamplerNeverDropsErrors(t *testing.T) {
+	fl := newFakeLogger()
+
+	failing := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	mw := RequestLogger(LogRequestBody, WithSampler(func(r *http.Request) float64 { return 0 }))(failing)
+
+	r := httptest.NewRequest(http.MethodGet, "/widgets", nil)
+	r = r.WithContext(logger.NewContext(r.Context(), fl))
+	w := httptest.NewRecorder()
+
+	mw.ServeHTTP(w, r)
+
+	if len(fl.state.records) != 1 {
+		t.Fatalf("got %d log records, want 1 (errors are always logged regardless of sampling): %+v", len(fl.state.records), fl.state.records)
+	}
+}
+
+func TestWithSamplerNeverDropsSampledTraces(t *testing.T) {
+	fl := newFakeLogger()
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mw := RequestLogger(LogRequestBody, WithSampler(func(r *http.Request) float64 { return 0 }))(ok)
+
+	r := httptest.NewRequest(http.MethodGet, "/widgets", nil)
+	r.Header.Set(TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r = r.WithContext(logger.NewContext(r.Context(), fl))
+	w := httptest.NewRecorder()
+
+	mw.ServeHTTP(w, r)
+
+	if len(fl.state.records) != 1 {
+		t.Fatalf("got %d log records, want 1 (a sampled trace is always logged regardless of FnSample): %+v", len(fl.state.records), fl.state.records)
+	}
+}
@@ -0,0 +1,99 @@
+package httplog
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Header names for the trace propagation formats understood by DefaultExtractTrace.
+const (
+	TraceParentHeader = "traceparent"
+	B3SingleHeader    = "b3"
+	B3TraceIDHeader   = "X-B3-TraceId"
+	B3SpanIDHeader    = "X-B3-SpanId"
+	B3SampledHeader   = "X-B3-Sampled"
+)
+
+// FnExtractTrace extracts (or generates) the trace-id/span-id for a request, along with whether the
+// trace is sampled. Implementations may bring their own propagator (e.g. OpenTelemetry) in place of
+// DefaultExtractTrace.
+type FnExtractTrace func(r *http.Request) (traceID, spanID string, sampled bool)
+
+// DefaultExtractTrace parses the W3C Trace Context traceparent header, falling back to B3 headers
+// (single "b3" header or the X-B3-* triple), and generates a new trace/span id if neither is present.
+func DefaultExtractTrace(r *http.Request) (traceID, spanID string, sampled bool) {
+	if tp := r.Header.Get(TraceParentHeader); tp != "" {
+		if id, span, ok, sampledFlag := parseTraceParent(tp); ok {
+			return id, span, sampledFlag
+		}
+	}
+
+	if b3 := r.Header.Get(B3SingleHeader); b3 != "" {
+		if id, span, ok, sampledFlag := parseB3Single(b3); ok {
+			return id, span, sampledFlag
+		}
+	}
+
+	if id := r.Header.Get(B3TraceIDHeader); id != "" {
+		return id, r.Header.Get(B3SpanIDHeader), r.Header.Get(B3SampledHeader) == "1"
+	}
+
+	return newTraceID(), newSpanID(), false
+}
+
+// formatTraceParent renders a W3C traceparent header value, setting the sampled flag bit to match
+// sampled so downstream services propagating it make the same sampling decision.
+func formatTraceParent(traceID, spanID string, sampled bool) string {
+	flags := "00"
+	if sampled {
+		flags = "01"
+	}
+
+	return "00-" + traceID + "-" + spanID + "-" + flags
+}
+
+// parseTraceParent parses a "00-<trace-id>-<span-id>-<flags>" traceparent value.
+func parseTraceParent(v string) (traceID, spanID string, ok, sampled bool) {
+	parts := strings.Split(v, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return "", "", false, false
+	}
+
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return "", "", false, false
+	}
+
+	return parts[1], parts[2], true, flags[0]&0x01 == 0x01
+}
+
+// parseB3Single parses a single-header B3 value: "<trace-id>-<span-id>-<sampled>-<parent-span-id>".
+func parseB3Single(v string) (traceID, spanID string, ok, sampled bool) {
+	parts := strings.Split(v, "-")
+	if len(parts) < 2 {
+		return "", "", false, false
+	}
+
+	sampled = len(parts) >= 3 && parts[2] == "1"
+
+	return parts[0], parts[1], true, sampled
+}
+
+func newTraceID() string {
+	return randomHex(16)
+}
+
+func newSpanID() string {
+	return randomHex(8)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return strings.Repeat("0", n*2)
+	}
+
+	return hex.EncodeToString(b)
+}
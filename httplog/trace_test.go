@@ -0,0 +1,120 @@
+package httplog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		wantTraceID string
+		wantSpanID  string
+		wantOK      bool
+		wantSampled bool
+	}{
+		{
+			name:        "sampled",
+			value:       "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			wantTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantSpanID:  "00f067aa0ba902b7",
+			wantOK:      true,
+			wantSampled: true,
+		},
+		{
+			name:        "not sampled",
+			value:       "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			wantTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantSpanID:  "00f067aa0ba902b7",
+			wantOK:      true,
+			wantSampled: false,
+		},
+		{name: "too few parts", value: "00-4bf92f3577b34da6a3ce929d0e0e4736", wantOK: false},
+		{name: "bad flags", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz", wantOK: false},
+		{name: "empty", value: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traceID, spanID, ok, sampled := parseTraceParent(tt.value)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+
+			if !ok {
+				return
+			}
+
+			if traceID != tt.wantTraceID || spanID != tt.wantSpanID || sampled != tt.wantSampled {
+				t.Fatalf("got (%q, %q, %v), want (%q, %q, %v)", traceID, spanID, sampled, tt.wantTraceID, tt.wantSpanID, tt.wantSampled)
+			}
+		})
+	}
+}
+
+func TestParseB3Single(t *testing.T) {
+	traceID, spanID, ok, sampled := parseB3Single("80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1")
+	if !ok || traceID != "80f198ee56343ba864fe8b2a57d3eff7" || spanID != "e457b5a2e4d86bd1" || !sampled {
+		t.Fatalf("unexpected parse result: %q %q %v %v", traceID, spanID, ok, sampled)
+	}
+
+	if _, _, ok, _ := parseB3Single("nohyphen"); ok {
+		t.Fatalf("expected ok=false for a single segment")
+	}
+}
+
+func TestDefaultExtractTrace(t *testing.T) {
+	t.Run("traceparent takes priority", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		r.Header.Set(B3TraceIDHeader, "ignored")
+
+		traceID, spanID, sampled := DefaultExtractTrace(r)
+		if traceID != "4bf92f3577b34da6a3ce929d0e0e4736" || spanID != "00f067aa0ba902b7" || !sampled {
+			t.Fatalf("unexpected result: %q %q %v", traceID, spanID, sampled)
+		}
+	})
+
+	t.Run("b3 single header fallback", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(B3SingleHeader, "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1")
+
+		traceID, spanID, sampled := DefaultExtractTrace(r)
+		if traceID != "80f198ee56343ba864fe8b2a57d3eff7" || spanID != "e457b5a2e4d86bd1" || !sampled {
+			t.Fatalf("unexpected result: %q %q %v", traceID, spanID, sampled)
+		}
+	})
+
+	t.Run("b3 multi header fallback", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(B3TraceIDHeader, "80f198ee56343ba864fe8b2a57d3eff7")
+		r.Header.Set(B3SpanIDHeader, "e457b5a2e4d86bd1")
+		r.Header.Set(B3SampledHeader, "1")
+
+		traceID, spanID, sampled := DefaultExtractTrace(r)
+		if traceID != "80f198ee56343ba864fe8b2a57d3eff7" || spanID != "e457b5a2e4d86bd1" || !sampled {
+			t.Fatalf("unexpected result: %q %q %v", traceID, spanID, sampled)
+		}
+	})
+
+	t.Run("generates a new trace when nothing is present", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		traceID, spanID, sampled := DefaultExtractTrace(r)
+		if traceID == "" || spanID == "" || sampled {
+			t.Fatalf("expected generated ids and sampled=false, got %q %q %v", traceID, spanID, sampled)
+		}
+	})
+}
+
+func TestFormatTraceParent(t *testing.T) {
+	if got := formatTraceParent("trace", "span", true); got != "00-trace-span-01" {
+		t.Fatalf("sampled: got %q", got)
+	}
+
+	if got := formatTraceParent("trace", "span", false); got != "00-trace-span-00" {
+		t.Fatalf("not sampled: got %q", got)
+	}
+}